@@ -0,0 +1,92 @@
+/*
+Copyright © 2024 Matthew Booth
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mdbooth/polymorph/pkg/store"
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect and manage the polymorph cache",
+	Long:  ``,
+}
+
+var cacheLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List cached store entries",
+	Long:  ``,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := runCacheLs()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var cacheRmCmd = &cobra.Command{
+	Use:   "rm <digest>",
+	Short: "Remove a store entry by digest",
+	Long:  ``,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := runCacheRm(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func runCacheLs() error {
+	st, err := store.New()
+	if err != nil {
+		return err
+	}
+
+	entries, err := st.List()
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		fmt.Fprintf(os.Stdout, "sha256:%s\trefs=%d\t%s\n", e.Digest, e.RefCount, e.ModTime.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+func runCacheRm(digest string) error {
+	st, err := store.New()
+	if err != nil {
+		return err
+	}
+
+	return st.Remove(digest)
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheLsCmd)
+	cacheCmd.AddCommand(cacheRmCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
@@ -17,19 +17,19 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
-	"archive/tar"
-	"bytes"
-	"compress/gzip"
-	"errors"
+	"context"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
+	"os/signal"
 	"path"
 	"syscall"
-	"text/template"
 
 	"github.com/BurntSushi/toml"
+	"github.com/mdbooth/polymorph/pkg/archive"
+	"github.com/mdbooth/polymorph/pkg/provider/github"
+	"github.com/mdbooth/polymorph/pkg/provider/gitlab"
+	"github.com/mdbooth/polymorph/pkg/store"
+	"github.com/mdbooth/polymorph/pkg/templates"
 	"github.com/spf13/cobra"
 )
 
@@ -53,40 +53,30 @@ type ExecTemplate struct {
 	Params      map[string]string `toml:"params"`
 	Executables map[string]string `toml:"executables"`
 
-	TarballFetcher TarballFetcher `toml:"tarball"`
-}
+	// OSAliases and ArchAliases remap runtime.GOOS/GOARCH (e.g. "amd64" to
+	// "x86_64") before they're exposed to templates as .OS/.Arch/.OSArch.
+	OSAliases   map[string]string `toml:"os_aliases"`
+	ArchAliases map[string]string `toml:"arch_aliases"`
 
-type TarballFetcher struct {
-	URL string `toml:"url"`
+	ArchiveFetcher archive.Fetcher `toml:"archive"`
+	GitHub         *github.Config  `toml:"github"`
+	GitLab         *gitlab.Config  `toml:"gitlab"`
 }
 
 func runExec(_ *cobra.Command, args []string) error {
-	var err error
-
 	templateFile := args[0]
 	executableName := args[1]
 
-	execPath, fetchFunc, err := getConfig(executableName, templateFile)
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	execPath, err := resolveExecPath(ctx, executableName, templateFile)
 	if err != nil {
 		return err
 	}
 
 	execArgs := args[1:]
 	err = syscall.Exec(execPath, execArgs, os.Environ())
-	if !errors.Is(err, syscall.ENOENT) {
-		return fmt.Errorf("error executing %s: %w", executableName, err)
-	}
-
-	if fetchFunc == nil {
-		return fmt.Errorf("no fetcher specified")
-	}
-	err = fetchFunc()
-	if err != nil {
-		return fmt.Errorf("error fetching %s: %w", executableName, err)
-	}
-
-	// Should not return
-	err = syscall.Exec(execPath, execArgs, os.Environ())
 	return fmt.Errorf("error executing %s: %w", executableName, err)
 }
 
@@ -97,40 +87,42 @@ func readTemplateFile(path string) (*ExecTemplate, error) {
 	}
 
 	template := ExecTemplate{}
-	if _, err := toml.Decode(string(data), &template); err != nil {
+	md, err := toml.Decode(string(data), &template)
+	if err != nil {
 		return nil, err
 	}
+
+	for _, key := range md.Undecoded() {
+		if key.String() == "tarball" {
+			return nil, fmt.Errorf("%s: [tarball] was renamed to [archive] when pkg/tarball was generalized into pkg/archive; update the template", path)
+		}
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("%s: unrecognized field %q", path, undecoded[0])
+	}
+
 	return &template, nil
 }
 
-func getConfig(executableName string, templateFile string) (string, func() error, error) {
-	// Read templateFile into a Template struct
+// resolveExecPath returns the path of executableName as described by
+// templateFile, fetching and extracting it into the content-addressed
+// store first if it isn't already cached there.
+func resolveExecPath(ctx context.Context, executableName, templateFile string) (string, error) {
 	execTemplate, err := readTemplateFile(templateFile)
 	if err != nil {
-		return "", nil, fmt.Errorf("error reading template %s: %s", templateFile, err)
+		return "", fmt.Errorf("error reading template %s: %s", templateFile, err)
 	}
 
-	directoryTmpl, err := template.New("directory").Parse(execTemplate.Directory)
-	if err != nil {
-		return "", nil, fmt.Errorf("error parsing directory template from %s: %s", templateFile, err)
-	}
-
-	// create a writer which writes to the string variable directory
-	var directoryBytes bytes.Buffer
-	if err := directoryTmpl.Execute(&directoryBytes, execTemplate.Params); err != nil {
-		return "", nil, fmt.Errorf("error executing directory template from %s: %s", templateFile, err)
-	}
-	directory := directoryBytes.String()
+	tmplCtx := templates.NewContext(execTemplate.Params, execTemplate.OSAliases, execTemplate.ArchAliases)
 
-	cacheDir, err := os.UserCacheDir()
+	directory, err := templates.ExpandTemplate(execTemplate.Directory, tmplCtx)
 	if err != nil {
-		return "", nil, fmt.Errorf("error getting user cache dir: %s", err)
+		return "", fmt.Errorf("error expanding directory template from %s: %w", templateFile, err)
 	}
-	execCacheDir := path.Join(cacheDir, "polymorph", execTemplate.Name)
-	versionedCacheDir := path.Join(execCacheDir, directory)
 
-	fetcher := func() error {
-		return tarballFetcher(&execTemplate.TarballFetcher, execCacheDir, directory, execTemplate.Params)
+	st, err := store.New()
+	if err != nil {
+		return "", err
 	}
 
 	executableBase := path.Base(executableName)
@@ -139,98 +131,71 @@ func getConfig(executableName string, templateFile string) (string, func() error
 		executable = executableBase
 	}
 
-	return path.Join(versionedCacheDir, executable), fetcher, nil
-}
-
-func tarballFetcher(fetcher *TarballFetcher, cacheDir, directory string, params map[string]string) error {
-	var err error
-	err = os.MkdirAll(cacheDir, 0755)
-	if err != nil {
-		return fmt.Errorf("error creating cache dir %s: %w", cacheDir, err)
-	}
-
-	tempDir, err := os.MkdirTemp(cacheDir, directory)
-	if err != nil {
-		return fmt.Errorf("error creating temporary directory %s: %w", path.Join(cacheDir, directory), err)
+	if entryDir, ok := st.Resolve(execTemplate.Name, directory); ok {
+		return path.Join(entryDir, executable), nil
 	}
-	defer os.RemoveAll(tempDir)
 
-	tarballURL, err := expandTemplate(fetcher.URL, params)
+	// Not cached yet: take the per-template lock so two concurrent execs
+	// of the same template don't race fetching and extracting it, then
+	// check again in case a sibling process populated it while we waited.
+	lock, err := st.Lock(execTemplate.Name)
 	if err != nil {
-		return fmt.Errorf("error expanding tarball fetch url template: %w", err)
+		return "", err
 	}
+	defer lock.Unlock()
 
-	fmt.Fprintf(os.Stderr, "Downloading tarball from %s...\n", tarballURL)
-
-	resp, err := http.Get(tarballURL)
-	if err != nil {
-		return fmt.Errorf("error downloading tarball %s: %w", tarballURL, err)
+	if entryDir, ok := st.Resolve(execTemplate.Name, directory); ok {
+		return path.Join(entryDir, executable), nil
 	}
-	defer resp.Body.Close()
 
-	uncompressed, err := gzip.NewReader(resp.Body)
+	entryDir, err := fetchArchive(ctx, st, execTemplate, tmplCtx, directory)
 	if err != nil {
-		return fmt.Errorf("error creating gzip reader: %w", err)
+		return "", fmt.Errorf("error fetching %s: %w", executableName, err)
 	}
-	defer uncompressed.Close()
 
-	if err := untar(uncompressed, tempDir); err != nil {
-		return err
-	}
-
-	targetDir := path.Join(cacheDir, directory)
-	return os.Rename(tempDir, targetDir)
+	return path.Join(entryDir, executable), nil
 }
 
-func untar(r io.Reader, dir string) error {
-	tarReader := tar.NewReader(r)
-	for {
-		header, err := tarReader.Next()
-		if err == io.EOF {
-			return nil
-		} else if err != nil {
-			return fmt.Errorf("error reading tarball: %w", err)
-		}
+// fetchArchive resolves execTemplate's fetcher (a literal archive, or a
+// github/gitlab release) and fetches it into a fresh store entry.
+func fetchArchive(ctx context.Context, st *store.Store, execTemplate *ExecTemplate, tmplCtx templates.Context, directory string) (string, error) {
+	fetcherConfig := execTemplate.ArchiveFetcher
 
-		target := path.Join(dir, header.Name)
-		mode := os.FileMode(header.Mode)
-
-		switch header.Typeflag {
-		case tar.TypeDir:
-			err = os.MkdirAll(target, mode)
-			if err != nil {
-				return fmt.Errorf("error creating directory %s: %w", target, err)
-			}
-
-		case tar.TypeReg:
-			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY, mode)
-			if err != nil {
-				return fmt.Errorf("error creating file %s: %w", target, err)
-			}
-
-			if _, err := io.Copy(f, tarReader); err != nil {
-				return fmt.Errorf("error writing file %s: %w", target, err)
-			}
-			if err := f.Close(); err != nil {
-				return fmt.Errorf("error closing file %s: %w", target, err)
-			}
+	switch {
+	case execTemplate.GitHub != nil:
+		url, err := github.ResolveURL(ctx, execTemplate.GitHub, tmplCtx)
+		if err != nil {
+			return "", fmt.Errorf("error resolving github release: %w", err)
 		}
-	}
-}
+		fetcherConfig.URL = url
 
-func expandTemplate(templateString string, params map[string]string) (string, error) {
-	t, err := template.New("").Parse(templateString)
+	case execTemplate.GitLab != nil:
+		url, err := gitlab.ResolveURL(ctx, execTemplate.GitLab, tmplCtx)
+		if err != nil {
+			return "", fmt.Errorf("error resolving gitlab release: %w", err)
+		}
+		fetcherConfig.URL = url
+	}
+
+	// tempDir is intentionally not removed on error: a download
+	// interrupted by cancellation or a transient failure stays there so
+	// the next invocation's Range/If-Range request can resume it instead
+	// of starting over. archive.Fetch wipes any other stale content left
+	// by a previous attempt's partial extraction before extracting into
+	// it. Adopt consumes tempDir itself on success, either by renaming it
+	// into the store or removing it once it has been deduplicated against
+	// an existing entry.
+	tempDir, err := st.TempDir(execTemplate.Name)
 	if err != nil {
 		return "", err
 	}
 
-	var buf bytes.Buffer
-	err = t.Execute(&buf, params)
+	digest, err := archive.Fetch(ctx, &fetcherConfig, tmplCtx, tempDir)
 	if err != nil {
 		return "", err
 	}
 
-	return buf.String(), nil
+	return st.Adopt(digest, execTemplate.Name, directory, tempDir)
 }
 
 func init() {
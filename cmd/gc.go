@@ -0,0 +1,85 @@
+/*
+Copyright © 2024 Matthew Booth
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mdbooth/polymorph/pkg/store"
+	"github.com/spf13/cobra"
+)
+
+var gcOlderThan string
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove unreferenced cache entries",
+	Long:  ``,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := runGC()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func runGC() error {
+	age, err := parseAge(gcOlderThan)
+	if err != nil {
+		return fmt.Errorf("error parsing --older-than %q: %w", gcOlderThan, err)
+	}
+
+	st, err := store.New()
+	if err != nil {
+		return err
+	}
+
+	removed, err := st.GC(age)
+	if err != nil {
+		return err
+	}
+
+	for _, digest := range removed {
+		fmt.Fprintf(os.Stdout, "removed sha256:%s\n", digest)
+	}
+
+	return nil
+}
+
+// parseAge parses a duration like time.ParseDuration, plus a "d" suffix
+// for days (e.g. "30d"), which time.ParseDuration doesn't support.
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(s)
+}
+
+func init() {
+	gcCmd.Flags().StringVar(&gcOlderThan, "older-than", "30d", "remove unreferenced entries older than this (e.g. 30d, 12h)")
+	rootCmd.AddCommand(gcCmd)
+}
@@ -0,0 +1,252 @@
+// Package archive fetches and extracts a compressed or archived artifact,
+// dispatching on the URL's extension or an explicit format.
+package archive
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/mdbooth/polymorph/pkg/httpfetch"
+	"github.com/mdbooth/polymorph/pkg/integrity"
+	"github.com/mdbooth/polymorph/pkg/templates"
+)
+
+// Formats recognised by Fetch, either detected from the URL's extension or
+// set explicitly via the `format` TOML field.
+const (
+	FormatTarGz  = "tar.gz"
+	FormatTarXz  = "tar.xz"
+	FormatTarBz2 = "tar.bz2"
+	FormatTarZst = "tar.zst"
+	FormatZip    = "zip"
+	FormatGz     = "gz"
+)
+
+type Fetcher struct {
+	URL    string `toml:"url"`
+	Format string `toml:"format"`
+
+	// StripComponents removes the leading N path components from each
+	// archive entry, like tar's --strip-components=N.
+	StripComponents int `toml:"strip_components"`
+
+	SHA256            string `toml:"sha256"`
+	SHA256URL         string `toml:"sha256_url"`
+	MinisignPublicKey string `toml:"minisign_public_key"`
+	MinisignURL       string `toml:"minisign_url"`
+	CosignPublicKey   string `toml:"cosign_public_key"`
+	CosignURL         string `toml:"cosign_url"`
+
+	// Retries is a pointer so an explicit "retries = 0" (fail fast) is
+	// distinguishable from the field being absent from the template.
+	Retries           *int   `toml:"retries"`
+	RetryInitialDelay string `toml:"retry_initial_delay"`
+	RetryMaxDelay     string `toml:"retry_max_delay"`
+}
+
+// Fetch downloads, verifies and extracts the archive described by fetcher
+// into tempDir, and returns the hex-encoded SHA-256 digest of the
+// downloaded artifact so callers can content-address the result.
+func Fetch(ctx context.Context, fetcher *Fetcher, tmplCtx templates.Context, tempDir string) (string, error) {
+	archiveURL, err := templates.ExpandTemplate(fetcher.URL, tmplCtx)
+	if err != nil {
+		return "", fmt.Errorf("error expanding archive fetch url template: %w", err)
+	}
+
+	format := fetcher.Format
+	if format == "" {
+		format, err = formatFromURL(archiveURL)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	downloadPath := path.Join(tempDir, path.Base(archiveURL))
+	if err := cleanTempDir(tempDir, path.Base(downloadPath)); err != nil {
+		return "", err
+	}
+
+	digest, err := download(ctx, fetcher, archiveURL, downloadPath, tmplCtx)
+	if err != nil {
+		return "", err
+	}
+	defer removeDownload(downloadPath)
+
+	sigURL, err := fetcher.signatureURL(archiveURL, tmplCtx)
+	if err != nil {
+		return "", err
+	}
+	if sigURL != "" {
+		cfg := integrity.Config{
+			MinisignPublicKey: fetcher.MinisignPublicKey,
+			CosignPublicKey:   fetcher.CosignPublicKey,
+		}
+		if err := integrity.VerifySignature(ctx, cfg, downloadPath, sigURL); err != nil {
+			return "", err
+		}
+	}
+
+	var extractErr error
+	switch format {
+	case FormatTarGz:
+		extractErr = extractTarGz(downloadPath, tempDir, fetcher.StripComponents)
+	case FormatTarXz:
+		extractErr = extractTarXz(downloadPath, tempDir, fetcher.StripComponents)
+	case FormatTarBz2:
+		extractErr = extractTarBz2(downloadPath, tempDir, fetcher.StripComponents)
+	case FormatTarZst:
+		extractErr = extractTarZst(downloadPath, tempDir, fetcher.StripComponents)
+	case FormatZip:
+		extractErr = extractZip(downloadPath, tempDir, fetcher.StripComponents)
+	case FormatGz:
+		extractErr = extractGz(downloadPath, tempDir, strings.TrimSuffix(path.Base(archiveURL), ".gz"))
+	default:
+		extractErr = fmt.Errorf("unsupported archive format %q", format)
+	}
+	if extractErr != nil {
+		return "", extractErr
+	}
+
+	return digest, nil
+}
+
+func formatFromURL(url string) (string, error) {
+	name := strings.ToLower(path.Base(url))
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return FormatTarGz, nil
+	case strings.HasSuffix(name, ".tar.xz"):
+		return FormatTarXz, nil
+	case strings.HasSuffix(name, ".tar.bz2"):
+		return FormatTarBz2, nil
+	case strings.HasSuffix(name, ".tar.zst"):
+		return FormatTarZst, nil
+	case strings.HasSuffix(name, ".zip"):
+		return FormatZip, nil
+	case strings.HasSuffix(name, ".gz"):
+		return FormatGz, nil
+	default:
+		return "", fmt.Errorf("cannot determine archive format from url %q: set the format field explicitly", url)
+	}
+}
+
+func download(ctx context.Context, fetcher *Fetcher, archiveURL, downloadPath string, tmplCtx templates.Context) (string, error) {
+	fmt.Fprintf(os.Stderr, "Downloading archive from %s...\n", archiveURL)
+
+	opts, err := httpfetch.ParseOptions(fetcher.Retries, fetcher.RetryInitialDelay, fetcher.RetryMaxDelay)
+	if err != nil {
+		return "", fmt.Errorf("error parsing retry options: %w", err)
+	}
+
+	if err := httpfetch.Fetch(ctx, archiveURL, downloadPath, 0644, opts); err != nil {
+		return "", err
+	}
+
+	sum, err := integrity.SHA256File(downloadPath)
+	if err != nil {
+		return "", err
+	}
+
+	cfg := integrity.Config{SHA256: fetcher.SHA256}
+	if fetcher.SHA256URL != "" {
+		checksumURL, err := templates.ExpandTemplate(fetcher.SHA256URL, tmplCtx)
+		if err != nil {
+			return "", fmt.Errorf("error expanding sha256 checksum url template: %w", err)
+		}
+		cfg.SHA256URL = checksumURL
+	}
+
+	if err := integrity.VerifyChecksum(ctx, cfg, path.Base(downloadPath), sum); err != nil {
+		// The file is fully downloaded but fails verification: unlike a
+		// partial download, there's nothing to resume here, and leaving
+		// it behind would make the next attempt send a Range request for
+		// bytes past the end of what the server will re-serve, which
+		// most servers answer with a non-retryable 416.
+		removeDownload(downloadPath)
+		return "", err
+	}
+
+	return hex.EncodeToString(sum), nil
+}
+
+// removeDownload deletes downloadPath and its httpfetch-managed etag
+// sidecar file.
+func removeDownload(downloadPath string) {
+	os.Remove(downloadPath)
+	os.Remove(downloadPath + ".etag")
+}
+
+// cleanTempDir removes everything from tempDir except the in-progress
+// download named keep and its etag sidecar. tempDir is keyed only by
+// template name, not by digest or version, and is renamed whole into the
+// store on success, so any output left behind by a previous attempt that
+// failed partway through extraction would otherwise survive to be adopted
+// alongside this attempt's output.
+func cleanTempDir(tempDir, keep string) error {
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		return fmt.Errorf("error reading temp dir %s: %w", tempDir, err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == keep || name == keep+".etag" {
+			continue
+		}
+		if err := os.RemoveAll(path.Join(tempDir, name)); err != nil {
+			return fmt.Errorf("error cleaning stale temp dir entry %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func (fetcher *Fetcher) signatureURL(archiveURL string, tmplCtx templates.Context) (string, error) {
+	var tmpl string
+	switch {
+	case fetcher.MinisignPublicKey != "":
+		tmpl = fetcher.MinisignURL
+		if tmpl == "" {
+			tmpl = archiveURL + ".minisig"
+		}
+	case fetcher.CosignPublicKey != "":
+		tmpl = fetcher.CosignURL
+		if tmpl == "" {
+			tmpl = archiveURL + ".sig"
+		}
+	default:
+		return "", nil
+	}
+
+	return templates.ExpandTemplate(tmpl, tmplCtx)
+}
+
+// safeJoin joins dir and name, rejecting entries whose resolved path
+// escapes dir (Zip Slip / tar path traversal).
+func safeJoin(dir, name string) (string, error) {
+	target := path.Join(dir, name)
+	if target != dir && !strings.HasPrefix(target, dir+"/") {
+		return "", fmt.Errorf("archive entry %q escapes destination directory", name)
+	}
+	return target, nil
+}
+
+// stripPrefix removes the leading n path components from name. It reports
+// false if name has n or fewer components, meaning the entry should be
+// skipped entirely.
+func stripPrefix(name string, n int) (string, bool) {
+	if n <= 0 {
+		return name, true
+	}
+
+	parts := strings.Split(path.Clean(name), "/")
+	if len(parts) <= n {
+		return "", false
+	}
+
+	return path.Join(parts[n:]...), true
+}
@@ -0,0 +1,172 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestSafeJoinRejectsEscape(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"foo/bar", false},
+		{".", false},
+		{"../etc/passwd", true},
+		{"foo/../../etc/passwd", true},
+	}
+
+	for _, tt := range tests {
+		_, err := safeJoin("/tmp/dest", tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("safeJoin(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestStripPrefix(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+		want string
+		ok   bool
+	}{
+		{"a/b/c", 0, "a/b/c", true},
+		{"a/b/c", 1, "b/c", true},
+		{"a/b/c", 2, "c", true},
+		{"a/b/c", 3, "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := stripPrefix(tt.name, tt.n)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("stripPrefix(%q, %d) = (%q, %v), want (%q, %v)", tt.name, tt.n, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestUntarRejectsPathTraversal(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{
+		Name:     "../../etc/passwd",
+		Typeflag: tar.TypeReg,
+		Mode:     0644,
+		Size:     0,
+	}); err != nil {
+		t.Fatalf("error writing tar header: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("error closing tar writer: %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := untar(&buf, dir, 0); err == nil {
+		t.Fatal("untar of a path-traversal entry succeeded, want an error")
+	}
+}
+
+func TestExtractZipRejectsZipSlip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("../../etc/passwd")
+	if err != nil {
+		t.Fatalf("error creating zip entry: %v", err)
+	}
+	if _, err := w.Write([]byte("evil")); err != nil {
+		t.Fatalf("error writing zip entry: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("error closing zip writer: %v", err)
+	}
+
+	archivePath := path.Join(t.TempDir(), "evil.zip")
+	if err := os.WriteFile(archivePath, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("error writing %s: %v", archivePath, err)
+	}
+
+	dir := t.TempDir()
+	if err := extractZip(archivePath, dir, 0); err == nil {
+		t.Fatal("extractZip of a zip-slip entry succeeded, want an error")
+	}
+}
+
+func TestCleanTempDirKeepsOnlyTheDownload(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{"archive.tar.gz", "archive.tar.gz.etag", "bin", "extracted-dir"} {
+		p := path.Join(dir, name)
+		if name == "extracted-dir" {
+			if err := os.Mkdir(p, 0755); err != nil {
+				t.Fatalf("error creating %s: %v", p, err)
+			}
+			continue
+		}
+		if err := os.WriteFile(p, []byte("x"), 0644); err != nil {
+			t.Fatalf("error writing %s: %v", p, err)
+		}
+	}
+
+	if err := cleanTempDir(dir, "archive.tar.gz"); err != nil {
+		t.Fatalf("cleanTempDir: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("error reading %s: %v", dir, err)
+	}
+
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Name())
+	}
+	want := []string{"archive.tar.gz", "archive.tar.gz.etag"}
+	if len(got) != len(want) {
+		t.Fatalf("cleanTempDir left %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("cleanTempDir left %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func TestExtractZipPreservesExecutableBit(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	hdr := &zip.FileHeader{Name: "run.sh", Method: zip.Deflate}
+	hdr.SetMode(0755)
+	w, err := zw.CreateHeader(hdr)
+	if err != nil {
+		t.Fatalf("error creating zip entry: %v", err)
+	}
+	if _, err := w.Write([]byte("#!/bin/sh\n")); err != nil {
+		t.Fatalf("error writing zip entry: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("error closing zip writer: %v", err)
+	}
+
+	archivePath := path.Join(t.TempDir(), "bin.zip")
+	if err := os.WriteFile(archivePath, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("error writing %s: %v", archivePath, err)
+	}
+
+	dir := t.TempDir()
+	if err := extractZip(archivePath, dir, 0); err != nil {
+		t.Fatalf("extractZip: %v", err)
+	}
+
+	info, err := os.Stat(path.Join(dir, "run.sh"))
+	if err != nil {
+		t.Fatalf("error statting extracted file: %v", err)
+	}
+	if info.Mode().Perm()&0111 == 0 {
+		t.Errorf("extracted file mode %v lost the executable bit", info.Mode())
+	}
+}
@@ -0,0 +1,151 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+func extractTarGz(archivePath, dir string, stripComponents int) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("error opening %s: %w", archivePath, err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("error creating gzip reader: %w", err)
+	}
+	defer gz.Close()
+
+	return untar(gz, dir, stripComponents)
+}
+
+func extractTarXz(archivePath, dir string, stripComponents int) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("error opening %s: %w", archivePath, err)
+	}
+	defer f.Close()
+
+	xzr, err := xz.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("error creating xz reader: %w", err)
+	}
+
+	return untar(xzr, dir, stripComponents)
+}
+
+func extractTarBz2(archivePath, dir string, stripComponents int) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("error opening %s: %w", archivePath, err)
+	}
+	defer f.Close()
+
+	return untar(bzip2.NewReader(f), dir, stripComponents)
+}
+
+func extractTarZst(archivePath, dir string, stripComponents int) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("error opening %s: %w", archivePath, err)
+	}
+	defer f.Close()
+
+	zr, err := zstd.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("error creating zstd reader: %w", err)
+	}
+	defer zr.Close()
+
+	return untar(zr, dir, stripComponents)
+}
+
+// extractGz decompresses a bare (non-tar) gzip file to name under dir.
+func extractGz(archivePath, dir, name string) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("error opening %s: %w", archivePath, err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("error creating gzip reader: %w", err)
+	}
+	defer gz.Close()
+
+	target, err := safeJoin(dir, name)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return fmt.Errorf("error creating file %s: %w", target, err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, gz); err != nil {
+		return fmt.Errorf("error writing file %s: %w", target, err)
+	}
+
+	return out.Close()
+}
+
+func untar(r io.Reader, dir string, stripComponents int) error {
+	tarReader := tar.NewReader(r)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("error reading archive: %w", err)
+		}
+
+		name, ok := stripPrefix(header.Name, stripComponents)
+		if !ok {
+			continue
+		}
+
+		target, err := safeJoin(dir, name)
+		if err != nil {
+			return err
+		}
+
+		mode := os.FileMode(header.Mode)
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, mode); err != nil {
+				return fmt.Errorf("error creating directory %s: %w", target, err)
+			}
+
+		case tar.TypeReg:
+			if err := os.MkdirAll(path.Dir(target), 0755); err != nil {
+				return fmt.Errorf("error creating directory %s: %w", path.Dir(target), err)
+			}
+
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY, mode)
+			if err != nil {
+				return fmt.Errorf("error creating file %s: %w", target, err)
+			}
+
+			if _, err := io.Copy(f, tarReader); err != nil {
+				return fmt.Errorf("error writing file %s: %w", target, err)
+			}
+			if err := f.Close(); err != nil {
+				return fmt.Errorf("error closing file %s: %w", target, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package archive
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+)
+
+func extractZip(archivePath, dir string, stripComponents int) error {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("error opening zip %s: %w", archivePath, err)
+	}
+	defer r.Close()
+
+	for _, zf := range r.File {
+		name, ok := stripPrefix(zf.Name, stripComponents)
+		if !ok {
+			continue
+		}
+
+		target, err := safeJoin(dir, name)
+		if err != nil {
+			return err
+		}
+
+		if zf.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, zf.Mode()); err != nil {
+				return fmt.Errorf("error creating directory %s: %w", target, err)
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(path.Dir(target), 0755); err != nil {
+			return fmt.Errorf("error creating directory %s: %w", path.Dir(target), err)
+		}
+
+		if err := extractZipFile(zf, target); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// extractZipFile writes a single zip entry to target, preserving the
+// entry's Unix executable bit.
+func extractZipFile(zf *zip.File, target string) error {
+	src, err := zf.Open()
+	if err != nil {
+		return fmt.Errorf("error opening %s in zip: %w", zf.Name, err)
+	}
+	defer src.Close()
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, zf.Mode())
+	if err != nil {
+		return fmt.Errorf("error creating file %s: %w", target, err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, src); err != nil {
+		return fmt.Errorf("error writing file %s: %w", target, err)
+	}
+
+	return out.Close()
+}
@@ -0,0 +1,235 @@
+// Package httpfetch downloads a URL to a file with retries, exponential
+// backoff and resumable transfers, used by the archive fetcher.
+package httpfetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultRetries           = 3
+	DefaultRetryInitialDelay = time.Second
+	DefaultRetryMaxDelay     = 30 * time.Second
+)
+
+// Clock abstracts time so retry behavior can be driven deterministically in
+// tests.
+type Clock interface {
+	Sleep(d time.Duration)
+}
+
+type realClock struct{}
+
+func (realClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// Options configures retry behavior for Fetch. The zero value uses the
+// package defaults.
+type Options struct {
+	// Retries is the number of retries after the first attempt. nil means
+	// "not configured" and falls back to DefaultRetries; this is
+	// distinct from a pointer to 0, which means "fail fast, no retries".
+	Retries           *int
+	RetryInitialDelay time.Duration
+	RetryMaxDelay     time.Duration
+	Clock             Clock
+}
+
+// ParseOptions builds Options from the string durations used in TOML
+// templates, falling back to the package defaults for empty values.
+// retries is nil when the template left the retries field unset.
+func ParseOptions(retries *int, initialDelay, maxDelay string) (Options, error) {
+	opts := Options{Retries: retries}
+
+	if initialDelay == "" {
+		opts.RetryInitialDelay = DefaultRetryInitialDelay
+	} else {
+		d, err := time.ParseDuration(initialDelay)
+		if err != nil {
+			return Options{}, fmt.Errorf("error parsing retry_initial_delay %q: %w", initialDelay, err)
+		}
+		opts.RetryInitialDelay = d
+	}
+
+	if maxDelay == "" {
+		opts.RetryMaxDelay = DefaultRetryMaxDelay
+	} else {
+		d, err := time.ParseDuration(maxDelay)
+		if err != nil {
+			return Options{}, fmt.Errorf("error parsing retry_max_delay %q: %w", maxDelay, err)
+		}
+		opts.RetryMaxDelay = d
+	}
+
+	return opts, nil
+}
+
+func (o Options) withDefaults() Options {
+	if o.Retries == nil {
+		retries := DefaultRetries
+		o.Retries = &retries
+	}
+	if o.RetryInitialDelay == 0 {
+		o.RetryInitialDelay = DefaultRetryInitialDelay
+	}
+	if o.RetryMaxDelay == 0 {
+		o.RetryMaxDelay = DefaultRetryMaxDelay
+	}
+	if o.Clock == nil {
+		o.Clock = realClock{}
+	}
+	return o
+}
+
+// retryableError marks an error as transient, optionally carrying a
+// server-requested Retry-After delay.
+type retryableError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// Fetch downloads url into filePath, retrying on network errors and
+// 5xx/429 responses with exponential backoff and jitter, honoring
+// Retry-After. If filePath already contains a partial download, it is
+// resumed with a Range request guarded by If-Range against the server's
+// ETag, which is cached alongside filePath.
+func Fetch(ctx context.Context, url, filePath string, perm os.FileMode, opts Options) error {
+	opts = opts.withDefaults()
+
+	var lastErr error
+	for attempt := 0; attempt <= *opts.Retries; attempt++ {
+		if attempt > 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			opts.Clock.Sleep(backoff(opts, attempt, lastErr))
+		}
+
+		err := fetchOnce(ctx, url, filePath, perm)
+		if err == nil {
+			return nil
+		}
+
+		var re *retryableError
+		if !errors.As(err, &re) {
+			return err
+		}
+		lastErr = err
+	}
+
+	return fmt.Errorf("error fetching %s: giving up after %d attempts: %w", url, *opts.Retries+1, lastErr)
+}
+
+func fetchOnce(ctx context.Context, url, filePath string, perm os.FileMode) error {
+	etagPath := filePath + ".etag"
+
+	var resumeFrom int64
+	var etag string
+	if info, err := os.Stat(filePath); err == nil {
+		resumeFrom = info.Size()
+		if b, err := os.ReadFile(etagPath); err == nil {
+			etag = string(b)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request for %s: %w", url, err)
+	}
+
+	if resumeFrom > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", resumeFrom))
+		if etag != "" {
+			req.Header.Set("If-Range", etag)
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return &retryableError{err: fmt.Errorf("error downloading %s: %w", url, err)}
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		resumeFrom = 0
+	case http.StatusPartialContent:
+		// Resuming; keep resumeFrom as-is.
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return &retryableError{
+			err:        fmt.Errorf("error downloading %s: server returned %s", url, resp.Status),
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	default:
+		return fmt.Errorf("error downloading %s: server returned %s", url, resp.Status)
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY
+	if resumeFrom > 0 {
+		flags |= os.O_APPEND
+	} else {
+		flags |= os.O_TRUNC
+	}
+
+	f, err := os.OpenFile(filePath, flags, perm)
+	if err != nil {
+		return fmt.Errorf("error opening %s: %w", filePath, err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		return &retryableError{err: fmt.Errorf("error writing %s: %w", filePath, err)}
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("error closing %s: %w", filePath, err)
+	}
+
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		if err := os.WriteFile(etagPath, []byte(etag), 0644); err != nil {
+			return fmt.Errorf("error caching etag for %s: %w", filePath, err)
+		}
+	} else {
+		os.Remove(etagPath)
+	}
+
+	return nil
+}
+
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+func backoff(opts Options, attempt int, lastErr error) time.Duration {
+	var re *retryableError
+	if errors.As(lastErr, &re) && re.retryAfter > 0 {
+		return re.retryAfter
+	}
+
+	delay := opts.RetryInitialDelay * time.Duration(int64(1)<<uint(attempt-1))
+	if delay > opts.RetryMaxDelay || delay <= 0 {
+		delay = opts.RetryMaxDelay
+	}
+
+	return delay/2 + time.Duration(rand.Int63n(int64(delay)/2+1))
+}
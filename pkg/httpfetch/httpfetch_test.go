@@ -0,0 +1,129 @@
+package httpfetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock records requested sleeps instead of actually sleeping, so
+// retry/backoff behavior can be driven deterministically in tests.
+type fakeClock struct {
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.sleeps = append(c.sleeps, d)
+}
+
+func TestFetchRetriesOnServerError(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	filePath := path.Join(dir, "out")
+
+	retries := 3
+	clock := &fakeClock{}
+	opts := Options{Retries: &retries, RetryInitialDelay: time.Millisecond, RetryMaxDelay: time.Millisecond, Clock: clock}
+
+	if err := Fetch(context.Background(), srv.URL, filePath, 0644, opts); err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Errorf("server saw %d attempts, want 3", got)
+	}
+	if len(clock.sleeps) != 2 {
+		t.Errorf("clock recorded %d sleeps, want 2", len(clock.sleeps))
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("error reading %s: %v", filePath, err)
+	}
+	if string(data) != "ok" {
+		t.Errorf("downloaded content = %q, want %q", data, "ok")
+	}
+}
+
+func TestFetchZeroRetriesFailsFast(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	filePath := path.Join(dir, "out")
+
+	retries := 0
+	opts := Options{Retries: &retries, Clock: &fakeClock{}}
+
+	if err := Fetch(context.Background(), srv.URL, filePath, 0644, opts); err == nil {
+		t.Fatal("Fetch succeeded, want an error")
+	}
+
+	if got := atomic.LoadInt32(&attempts); got != 1 {
+		t.Errorf("server saw %d attempts, want 1 (retries = 0 means fail fast)", got)
+	}
+}
+
+func TestFetchResumesWithRange(t *testing.T) {
+	const full = "hello, world"
+
+	var gotRange string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotRange = r.Header.Get("Range")
+		if gotRange == "" {
+			w.Header().Set("ETag", `"etag1"`)
+			w.Write([]byte(full))
+			return
+		}
+		w.Header().Set("ETag", `"etag1"`)
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte(full[len("hello, "):]))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	filePath := path.Join(dir, "out")
+
+	if err := os.WriteFile(filePath, []byte("hello, "), 0644); err != nil {
+		t.Fatalf("error seeding partial download: %v", err)
+	}
+	if err := os.WriteFile(filePath+".etag", []byte(`"etag1"`), 0644); err != nil {
+		t.Fatalf("error seeding etag: %v", err)
+	}
+
+	retries := 1
+	opts := Options{Retries: &retries, Clock: &fakeClock{}}
+	if err := Fetch(context.Background(), srv.URL, filePath, 0644, opts); err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+
+	if gotRange != "bytes=7-" {
+		t.Errorf("Range header = %q, want %q", gotRange, "bytes=7-")
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("error reading %s: %v", filePath, err)
+	}
+	if string(data) != full {
+		t.Errorf("resumed content = %q, want %q", data, full)
+	}
+}
@@ -0,0 +1,208 @@
+// Package integrity verifies downloaded artifacts against a checksum and/or
+// a minisign or cosign signature, used by the archive fetcher.
+package integrity
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/jedisct1/go-minisign"
+)
+
+// SHA256File returns the SHA-256 digest of the file at filePath.
+func SHA256File(filePath string) ([]byte, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %w", filePath, err)
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return nil, fmt.Errorf("error hashing %s: %w", filePath, err)
+	}
+
+	return hasher.Sum(nil), nil
+}
+
+// Config holds the checksum and signature verification settings configured
+// on a Fetcher. Any field left empty disables that check.
+type Config struct {
+	SHA256            string
+	SHA256URL         string
+	MinisignPublicKey string
+	CosignPublicKey   string
+}
+
+// VerifyChecksum checks sum, the SHA-256 digest of a downloaded artifact,
+// against cfg.SHA256, or, if that is unset, a digest looked up for filename
+// in the `sha256sum -b` formatted file at cfg.SHA256URL. It is a no-op if
+// neither is configured.
+func VerifyChecksum(ctx context.Context, cfg Config, filename string, sum []byte) error {
+	expected := cfg.SHA256
+	if expected == "" && cfg.SHA256URL != "" {
+		var err error
+		expected, err = fetchChecksum(ctx, cfg.SHA256URL, filename)
+		if err != nil {
+			return err
+		}
+	}
+
+	if expected == "" {
+		return nil
+	}
+
+	digest := hex.EncodeToString(sum)
+	if !strings.EqualFold(expected, digest) {
+		return fmt.Errorf("sha256 checksum mismatch for %s: expected %s, got %s", filename, expected, digest)
+	}
+
+	return nil
+}
+
+func fetchChecksum(ctx context.Context, checksumURL, filename string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, checksumURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("error creating request for %s: %w", checksumURL, err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error downloading checksum file %s: %w", checksumURL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading checksum file %s: %w", checksumURL, err)
+	}
+
+	for _, line := range strings.Split(string(body), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if strings.TrimPrefix(fields[1], "*") == filename {
+			return fields[0], nil
+		}
+	}
+
+	return "", fmt.Errorf("no checksum for %s found in %s", filename, checksumURL)
+}
+
+// VerifySignature verifies filePath against a minisign or cosign signature
+// downloaded from sigURL, using whichever public key is configured in cfg.
+// It is a no-op if neither is configured.
+func VerifySignature(ctx context.Context, cfg Config, filePath, sigURL string) error {
+	switch {
+	case cfg.MinisignPublicKey != "":
+		return verifyMinisign(ctx, cfg.MinisignPublicKey, filePath, sigURL)
+	case cfg.CosignPublicKey != "":
+		return verifyCosign(ctx, cfg.CosignPublicKey, filePath, sigURL)
+	default:
+		return nil
+	}
+}
+
+func verifyMinisign(ctx context.Context, publicKey, filePath, sigURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sigURL, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request for %s: %w", sigURL, err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error downloading minisign signature %s: %w", sigURL, err)
+	}
+	defer resp.Body.Close()
+
+	sigData, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading minisign signature %s: %w", sigURL, err)
+	}
+
+	pub, err := minisign.NewPublicKey(publicKey)
+	if err != nil {
+		return fmt.Errorf("error parsing minisign public key: %w", err)
+	}
+
+	sig, err := minisign.DecodeSignature(string(sigData))
+	if err != nil {
+		return fmt.Errorf("error decoding minisign signature %s: %w", sigURL, err)
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("error reading %s for signature verification: %w", filePath, err)
+	}
+
+	ok, err := pub.Verify(data, sig)
+	if err != nil {
+		return fmt.Errorf("error verifying minisign signature of %s: %w", filePath, err)
+	}
+	if !ok {
+		return fmt.Errorf("minisign signature verification failed for %s", filePath)
+	}
+
+	return nil
+}
+
+func verifyCosign(ctx context.Context, publicKeyPEM, filePath, sigURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sigURL, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request for %s: %w", sigURL, err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error downloading cosign signature %s: %w", sigURL, err)
+	}
+	defer resp.Body.Close()
+
+	sigB64, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading cosign signature %s: %w", sigURL, err)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sigB64)))
+	if err != nil {
+		return fmt.Errorf("error decoding cosign signature %s: %w", sigURL, err)
+	}
+
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		return fmt.Errorf("error decoding cosign public key: not valid PEM")
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("error parsing cosign public key: %w", err)
+	}
+
+	ecdsaKey, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("cosign public key is not an ECDSA key")
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("error reading %s for signature verification: %w", filePath, err)
+	}
+	digest := sha256.Sum256(data)
+
+	if !ecdsa.VerifyASN1(ecdsaKey, digest[:], sig) {
+		return fmt.Errorf("cosign signature verification failed for %s", filePath)
+	}
+
+	return nil
+}
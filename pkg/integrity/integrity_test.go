@@ -0,0 +1,153 @@
+package integrity
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/jedisct1/go-minisign"
+)
+
+func TestVerifyChecksumLiteral(t *testing.T) {
+	sum := sha256.Sum256([]byte("content"))
+
+	cfg := Config{SHA256: "0a1b2c"}
+	if err := VerifyChecksum(context.Background(), cfg, "out", sum[:]); err == nil {
+		t.Fatal("VerifyChecksum succeeded with a mismatched checksum, want an error")
+	}
+
+	cfg = Config{SHA256: hex.EncodeToString(sum[:])}
+	if err := VerifyChecksum(context.Background(), cfg, "out", sum[:]); err != nil {
+		t.Fatalf("VerifyChecksum: %v", err)
+	}
+}
+
+func TestVerifyChecksumIsNoopWhenUnconfigured(t *testing.T) {
+	sum := sha256.Sum256([]byte("content"))
+	if err := VerifyChecksum(context.Background(), Config{}, "out", sum[:]); err != nil {
+		t.Fatalf("VerifyChecksum: %v", err)
+	}
+}
+
+func TestVerifyChecksumFromURL(t *testing.T) {
+	sum := sha256.Sum256([]byte("content"))
+	digest := hex.EncodeToString(sum[:])
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(digest + "  out\n" + "deadbeef  other\n"))
+	}))
+	defer srv.Close()
+
+	cfg := Config{SHA256URL: srv.URL}
+	if err := VerifyChecksum(context.Background(), cfg, "out", sum[:]); err != nil {
+		t.Fatalf("VerifyChecksum: %v", err)
+	}
+
+	if err := VerifyChecksum(context.Background(), cfg, "missing", sum[:]); err == nil {
+		t.Fatal("VerifyChecksum succeeded for a file absent from the checksum listing, want an error")
+	}
+}
+
+func TestVerifySignatureMinisign(t *testing.T) {
+	const data = "artifact contents"
+
+	sk, err := minisign.DecodePrivateKey(testUnencryptedSK)
+	if err != nil {
+		t.Fatalf("DecodePrivateKey: %v", err)
+	}
+	sig, err := sk.Sign([]byte(data), minisign.SignOptions{TrustedComment: "test"})
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(sig.Encode())
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	filePath := path.Join(dir, "artifact")
+	if err := os.WriteFile(filePath, []byte(data), 0644); err != nil {
+		t.Fatalf("error writing %s: %v", filePath, err)
+	}
+
+	cfg := Config{MinisignPublicKey: testUnencryptedPKBase64}
+	if err := VerifySignature(context.Background(), cfg, filePath, srv.URL); err != nil {
+		t.Fatalf("VerifySignature: %v", err)
+	}
+
+	if err := os.WriteFile(filePath, []byte("tampered"), 0644); err != nil {
+		t.Fatalf("error writing %s: %v", filePath, err)
+	}
+	if err := VerifySignature(context.Background(), cfg, filePath, srv.URL); err == nil {
+		t.Fatal("VerifySignature succeeded against tampered content, want an error")
+	}
+}
+
+func TestVerifySignatureCosign(t *testing.T) {
+	const data = "artifact contents"
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	pubDER, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey: %v", err)
+	}
+	pubPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+
+	digest := sha256.Sum256([]byte(data))
+	rawSig, err := ecdsa.SignASN1(rand.Reader, priv, digest[:])
+	if err != nil {
+		t.Fatalf("SignASN1: %v", err)
+	}
+	sigB64 := base64.StdEncoding.EncodeToString(rawSig)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(sigB64))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	filePath := path.Join(dir, "artifact")
+	if err := os.WriteFile(filePath, []byte(data), 0644); err != nil {
+		t.Fatalf("error writing %s: %v", filePath, err)
+	}
+
+	cfg := Config{CosignPublicKey: string(pubPEM)}
+	if err := VerifySignature(context.Background(), cfg, filePath, srv.URL); err != nil {
+		t.Fatalf("VerifySignature: %v", err)
+	}
+
+	if err := os.WriteFile(filePath, []byte("tampered"), 0644); err != nil {
+		t.Fatalf("error writing %s: %v", filePath, err)
+	}
+	if err := VerifySignature(context.Background(), cfg, filePath, srv.URL); err == nil {
+		t.Fatal("VerifySignature succeeded against tampered content, want an error")
+	}
+}
+
+// Unencrypted test keypair generated with `minisign -G -W`; not used for
+// anything but this test.
+const (
+	testUnencryptedSK = `untrusted comment: minisign encrypted secret key
+RWQAAEIyAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAOItWpGuGQbG4C9WXaxEYLgZ2xxuqfbuZmDgAhQ8Unot8t7SyxZ0nVh0gESesJ6Ay57fGFJ9T1ajVmanT7MFMCCDbPZ8uqDcSAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=
+`
+	// testUnencryptedPKBase64 is the bare, comment-stripped form that
+	// Config.MinisignPublicKey expects (minisign.NewPublicKey, not
+	// DecodePublicKey).
+	testUnencryptedPKBase64 = "RWQ4i1aka4ZBsR0gESesJ6Ay57fGFJ9T1ajVmanT7MFMCCDbPZ8uqDcS"
+)
@@ -0,0 +1,171 @@
+// Package github resolves a GitHub release's version and asset URL so a
+// template can select a repo and asset pattern instead of a literal
+// download URL.
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/mdbooth/polymorph/pkg/provider/internal/resolve"
+	"github.com/mdbooth/polymorph/pkg/templates"
+)
+
+// apiBaseURL is the GitHub API root, overridden in tests to point at an
+// httptest server.
+var apiBaseURL = "https://api.github.com"
+
+// Config selects a GitHub release asset instead of a literal URL, e.g.
+//
+//	[github]
+//	repo = "kubernetes/kubectl"
+//	asset = "kubectl-{{.OS}}-{{.Arch}}.tar.gz"
+//	version = "latest"
+type Config struct {
+	Repo    string `toml:"repo"`
+	Asset   string `toml:"asset"`
+	Version string `toml:"version"`
+}
+
+type release struct {
+	TagName string  `json:"tag_name"`
+	Assets  []asset `json:"assets"`
+}
+
+type asset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+// ResolveURL resolves cfg's repo, version and asset pattern against the
+// GitHub releases API and returns the concrete download URL for the
+// current OS/Arch, as aliased by tmplCtx.
+func ResolveURL(ctx context.Context, cfg *Config, tmplCtx templates.Context) (string, error) {
+	rel, err := resolveRelease(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+
+	for _, assetCtx := range resolve.AssetContexts(tmplCtx) {
+		assetName, err := templates.ExpandTemplate(cfg.Asset, assetCtx)
+		if err != nil {
+			return "", fmt.Errorf("error expanding asset template for %s: %w", cfg.Repo, err)
+		}
+
+		for _, a := range rel.Assets {
+			if a.Name == assetName {
+				return a.BrowserDownloadURL, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("no asset matching %q found in %s release %s", cfg.Asset, cfg.Repo, rel.TagName)
+}
+
+func resolveRelease(ctx context.Context, cfg *Config) (*release, error) {
+	switch {
+	case cfg.Version == "" || cfg.Version == "latest":
+		return getRelease(ctx, fmt.Sprintf("%s/repos/%s/releases/latest", apiBaseURL, cfg.Repo))
+	case resolve.IsConstraint(cfg.Version):
+		return resolveConstraint(ctx, cfg.Repo, cfg.Version)
+	default:
+		return getRelease(ctx, fmt.Sprintf("%s/repos/%s/releases/tags/%s", apiBaseURL, cfg.Repo, cfg.Version))
+	}
+}
+
+func resolveConstraint(ctx context.Context, repo, constraintStr string) (*release, error) {
+	constraints, err := resolve.ParseConstraints(constraintStr)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing version constraint for %s: %w", repo, err)
+	}
+
+	releases, err := listReleases(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *release
+	var bestVersion resolve.Version
+	for i, rel := range releases {
+		v, ok := resolve.ParseVersion(rel.TagName)
+		if !ok || !resolve.MatchesAll(v, constraints) {
+			continue
+		}
+		if best == nil || v.Compare(bestVersion) > 0 {
+			best, bestVersion = &releases[i], v
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("no release of %s matches constraint %q", repo, constraintStr)
+	}
+
+	return best, nil
+}
+
+func listReleases(ctx context.Context, repo string) ([]release, error) {
+	url := fmt.Sprintf("%s/repos/%s/releases?per_page=100", apiBaseURL, repo)
+
+	req, err := newRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error listing releases for %s: %w", repo, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error listing releases for %s: server returned %s", repo, resp.Status)
+	}
+
+	var releases []release
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, fmt.Errorf("error decoding releases for %s: %w", repo, err)
+	}
+
+	return releases, nil
+}
+
+func getRelease(ctx context.Context, url string) (*release, error) {
+	req, err := newRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching release from %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching release from %s: server returned %s", url, resp.Status)
+	}
+
+	var rel release
+	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
+		return nil, fmt.Errorf("error decoding release from %s: %w", url, err)
+	}
+
+	return &rel, nil
+}
+
+func newRequest(ctx context.Context, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request for %s: %w", url, err)
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return req, nil
+}
@@ -0,0 +1,175 @@
+// Package gitlab resolves a GitLab release's version and asset URL so a
+// template can select a project and asset pattern instead of a literal
+// download URL. It mirrors pkg/provider/github.
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/mdbooth/polymorph/pkg/provider/internal/resolve"
+	"github.com/mdbooth/polymorph/pkg/templates"
+)
+
+// apiBaseURL is the GitLab API root, overridden in tests to point at an
+// httptest server.
+var apiBaseURL = "https://gitlab.com"
+
+// Config selects a GitLab release asset instead of a literal URL, e.g.
+//
+//	[gitlab]
+//	project = "gitlab-org/cli"
+//	asset = "glab_{{.OS}}_{{.Arch}}.tar.gz"
+//	version = "latest"
+type Config struct {
+	Project string `toml:"project"`
+	Asset   string `toml:"asset"`
+	Version string `toml:"version"`
+}
+
+type release struct {
+	TagName string `json:"tag_name"`
+	Assets  struct {
+		Links []link `json:"links"`
+	} `json:"assets"`
+}
+
+type link struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// ResolveURL resolves cfg's project, version and asset pattern against the
+// GitLab releases API and returns the concrete download URL for the
+// current OS/Arch, as aliased by tmplCtx.
+func ResolveURL(ctx context.Context, cfg *Config, tmplCtx templates.Context) (string, error) {
+	rel, err := resolveRelease(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+
+	for _, assetCtx := range resolve.AssetContexts(tmplCtx) {
+		assetName, err := templates.ExpandTemplate(cfg.Asset, assetCtx)
+		if err != nil {
+			return "", fmt.Errorf("error expanding asset template for %s: %w", cfg.Project, err)
+		}
+
+		for _, l := range rel.Assets.Links {
+			if l.Name == assetName {
+				return l.URL, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("no asset matching %q found in %s release %s", cfg.Asset, cfg.Project, rel.TagName)
+}
+
+func resolveRelease(ctx context.Context, cfg *Config) (*release, error) {
+	project := url.PathEscape(cfg.Project)
+
+	switch {
+	case cfg.Version == "" || cfg.Version == "latest":
+		return getRelease(ctx, fmt.Sprintf("%s/api/v4/projects/%s/releases/permalink/latest", apiBaseURL, project))
+	case resolve.IsConstraint(cfg.Version):
+		return resolveConstraint(ctx, cfg.Project, cfg.Version)
+	default:
+		return getRelease(ctx, fmt.Sprintf("%s/api/v4/projects/%s/releases/%s", apiBaseURL, project, url.PathEscape(cfg.Version)))
+	}
+}
+
+func resolveConstraint(ctx context.Context, project, constraintStr string) (*release, error) {
+	constraints, err := resolve.ParseConstraints(constraintStr)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing version constraint for %s: %w", project, err)
+	}
+
+	releases, err := listReleases(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *release
+	var bestVersion resolve.Version
+	for i, rel := range releases {
+		v, ok := resolve.ParseVersion(rel.TagName)
+		if !ok || !resolve.MatchesAll(v, constraints) {
+			continue
+		}
+		if best == nil || v.Compare(bestVersion) > 0 {
+			best, bestVersion = &releases[i], v
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("no release of %s matches constraint %q", project, constraintStr)
+	}
+
+	return best, nil
+}
+
+func listReleases(ctx context.Context, project string) ([]release, error) {
+	reqURL := fmt.Sprintf("%s/api/v4/projects/%s/releases?per_page=100", apiBaseURL, url.PathEscape(project))
+
+	req, err := newRequest(ctx, reqURL)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error listing releases for %s: %w", project, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error listing releases for %s: server returned %s", project, resp.Status)
+	}
+
+	var releases []release
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, fmt.Errorf("error decoding releases for %s: %w", project, err)
+	}
+
+	return releases, nil
+}
+
+func getRelease(ctx context.Context, url string) (*release, error) {
+	req, err := newRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching release from %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching release from %s: server returned %s", url, resp.Status)
+	}
+
+	var rel release
+	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
+		return nil, fmt.Errorf("error decoding release from %s: %w", url, err)
+	}
+
+	return &rel, nil
+}
+
+func newRequest(ctx context.Context, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request for %s: %w", url, err)
+	}
+
+	if token := os.Getenv("GITLAB_TOKEN"); token != "" {
+		req.Header.Set("PRIVATE-TOKEN", token)
+	}
+
+	return req, nil
+}
@@ -0,0 +1,154 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mdbooth/polymorph/pkg/templates"
+)
+
+func withTestServer(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	original := apiBaseURL
+	apiBaseURL = srv.URL
+	t.Cleanup(func() { apiBaseURL = original })
+}
+
+func writeRelease(t *testing.T, w http.ResponseWriter, tagName string, linkNames []string) {
+	t.Helper()
+	var rel release
+	rel.TagName = tagName
+	for _, name := range linkNames {
+		rel.Assets.Links = append(rel.Assets.Links, link{Name: name, URL: "https://example.com/" + name})
+	}
+	if err := json.NewEncoder(w).Encode(rel); err != nil {
+		t.Fatalf("error encoding release: %v", err)
+	}
+}
+
+func TestResolveURLLatest(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/projects/group/project/releases/permalink/latest" {
+			t.Errorf("unexpected request path %q", r.URL.Path)
+		}
+		writeRelease(t, w, "v1.2.3", []string{"tool-linux-amd64"})
+	})
+
+	cfg := &Config{Project: "group/project", Asset: "tool-{{.OSArch}}"}
+	tmplCtx := templates.Context{OS: "linux", Arch: "amd64", OSArch: "linux-amd64"}
+
+	got, err := ResolveURL(context.Background(), cfg, tmplCtx)
+	if err != nil {
+		t.Fatalf("ResolveURL: %v", err)
+	}
+	if want := "https://example.com/tool-linux-amd64"; got != want {
+		t.Errorf("ResolveURL = %q, want %q", got, want)
+	}
+}
+
+func TestResolveURLPinnedVersion(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/projects/group/project/releases/v1.0.0" {
+			t.Errorf("unexpected request path %q", r.URL.Path)
+		}
+		writeRelease(t, w, "v1.0.0", []string{"tool-linux-amd64"})
+	})
+
+	cfg := &Config{Project: "group/project", Asset: "tool-{{.OSArch}}", Version: "v1.0.0"}
+	tmplCtx := templates.Context{OS: "linux", Arch: "amd64", OSArch: "linux-amd64"}
+
+	got, err := ResolveURL(context.Background(), cfg, tmplCtx)
+	if err != nil {
+		t.Fatalf("ResolveURL: %v", err)
+	}
+	if want := "https://example.com/tool-linux-amd64"; got != want {
+		t.Errorf("ResolveURL = %q, want %q", got, want)
+	}
+}
+
+func TestResolveURLConstraint(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/projects/group/project/releases" {
+			t.Errorf("unexpected request path %q", r.URL.Path)
+			return
+		}
+		releases := []release{}
+		for _, tag := range []string{"v1.30.0", "v1.29.5", "v1.29.0"} {
+			var rel release
+			rel.TagName = tag
+			rel.Assets.Links = []link{{Name: "tool-linux-amd64", URL: "https://example.com/" + tag}}
+			releases = append(releases, rel)
+		}
+		if err := json.NewEncoder(w).Encode(releases); err != nil {
+			t.Fatalf("error encoding releases: %v", err)
+		}
+	})
+
+	cfg := &Config{Project: "group/project", Asset: "tool-{{.OSArch}}", Version: ">=1.29,<1.30"}
+	tmplCtx := templates.Context{OS: "linux", Arch: "amd64", OSArch: "linux-amd64"}
+
+	got, err := ResolveURL(context.Background(), cfg, tmplCtx)
+	if err != nil {
+		t.Fatalf("ResolveURL: %v", err)
+	}
+	if want := "https://example.com/v1.29.5"; got != want {
+		t.Errorf("ResolveURL = %q, want the highest release matching the constraint %q", got, want)
+	}
+}
+
+func TestResolveURLTriesArchAliases(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeRelease(t, w, "v1.0.0", []string{"tool-linux-x86_64"})
+	})
+
+	cfg := &Config{Project: "group/project", Asset: "tool-{{.OS}}-{{.Arch}}"}
+	tmplCtx := templates.Context{OS: "linux", Arch: "amd64", OSArch: "linux-amd64"}
+
+	got, err := ResolveURL(context.Background(), cfg, tmplCtx)
+	if err != nil {
+		t.Fatalf("ResolveURL: %v", err)
+	}
+	if want := "https://example.com/tool-linux-x86_64"; got != want {
+		t.Errorf("ResolveURL = %q, want %q", got, want)
+	}
+}
+
+func TestResolveURLHonorsConfiguredArchAlias(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeRelease(t, w, "v1.0.0", []string{"tool-linux-x64"})
+	})
+
+	cfg := &Config{Project: "group/project", Asset: "tool-{{.OS}}-{{.Arch}}"}
+	// Simulates a template with arch_aliases = { amd64 = "x64" }: the
+	// context already carries the configured alias by the time
+	// ResolveURL sees it, and that value - not the hardcoded
+	// amd64/x86_64 fallback - should be tried.
+	tmplCtx := templates.Context{OS: "linux", Arch: "x64", OSArch: "linux-x64"}
+
+	got, err := ResolveURL(context.Background(), cfg, tmplCtx)
+	if err != nil {
+		t.Fatalf("ResolveURL: %v", err)
+	}
+	if want := "https://example.com/tool-linux-x64"; got != want {
+		t.Errorf("ResolveURL = %q, want %q", got, want)
+	}
+}
+
+func TestResolveURLNoMatchingAsset(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeRelease(t, w, "v1.0.0", []string{"tool-darwin-arm64"})
+	})
+
+	cfg := &Config{Project: "group/project", Asset: "tool-{{.OSArch}}"}
+	tmplCtx := templates.Context{OS: "linux", Arch: "amd64", OSArch: "linux-amd64"}
+
+	if _, err := ResolveURL(context.Background(), cfg, tmplCtx); err == nil {
+		t.Fatal("ResolveURL succeeded with no matching asset, want an error")
+	}
+}
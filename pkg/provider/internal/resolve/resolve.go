@@ -0,0 +1,160 @@
+// Package resolve holds the version-constraint parsing and OS/arch alias
+// matching shared by the github and gitlab release providers.
+package resolve
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mdbooth/polymorph/pkg/templates"
+)
+
+// Version is a parsed major.minor.patch release tag.
+type Version [3]int
+
+// ParseVersion parses tag as a semver-ish version, tolerating a leading
+// "v" and discarding any pre-release or build metadata suffix.
+func ParseVersion(tag string) (Version, bool) {
+	tag = strings.TrimPrefix(tag, "v")
+	tag = strings.SplitN(tag, "-", 2)[0]
+	tag = strings.SplitN(tag, "+", 2)[0]
+
+	var v Version
+	fields := strings.SplitN(tag, ".", 3)
+	if len(fields) == 0 {
+		return v, false
+	}
+
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return v, false
+		}
+		v[i] = n
+	}
+
+	return v, true
+}
+
+// Compare returns -1, 0 or 1 as v is less than, equal to, or greater than
+// other.
+func (v Version) Compare(other Version) int {
+	for i := range v {
+		if v[i] != other[i] {
+			if v[i] < other[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// Constraint is a single comparator term of a version constraint, e.g.
+// the ">=1.29" in ">=1.29,<1.30".
+type Constraint struct {
+	op  string
+	ver Version
+}
+
+// IsConstraint reports whether version looks like a semver constraint
+// (e.g. ">=1.29,<1.30") rather than a literal tag or "latest".
+func IsConstraint(version string) bool {
+	return strings.ContainsAny(version, "<>=,")
+}
+
+// ParseConstraints parses a comma-separated list of comparator terms.
+func ParseConstraints(s string) ([]Constraint, error) {
+	var constraints []Constraint
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		op, verStr := splitOp(part)
+		v, ok := ParseVersion(verStr)
+		if !ok {
+			return nil, fmt.Errorf("invalid version constraint %q", part)
+		}
+		constraints = append(constraints, Constraint{op: op, ver: v})
+	}
+	return constraints, nil
+}
+
+func splitOp(s string) (string, string) {
+	for _, op := range []string{">=", "<=", "==", ">", "<", "="} {
+		if strings.HasPrefix(s, op) {
+			return op, strings.TrimPrefix(s, op)
+		}
+	}
+	return "=", s
+}
+
+// Matches reports whether v satisfies the constraint term.
+func (c Constraint) Matches(v Version) bool {
+	cmp := v.Compare(c.ver)
+	switch c.op {
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	default:
+		return cmp == 0
+	}
+}
+
+// MatchesAll reports whether v satisfies every constraint term.
+func MatchesAll(v Version, constraints []Constraint) bool {
+	for _, c := range constraints {
+		if !c.Matches(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// ArchAliases returns arch plus any aliases release maintainers commonly
+// publish assets under, in the order they should be tried.
+func ArchAliases(arch string) []string {
+	switch arch {
+	case "amd64":
+		return []string{"amd64", "x86_64"}
+	case "arm64":
+		return []string{"arm64", "aarch64"}
+	default:
+		return []string{arch}
+	}
+}
+
+// AssetContexts returns the template contexts to try, in order, when
+// matching a release asset name against an asset name pattern. tmplCtx's
+// OS and Arch already reflect any os_aliases/arch_aliases the template
+// configured, so that exact context is tried first; the remaining
+// contexts vary only Arch over ArchAliases(tmplCtx.Arch), to also catch
+// the common alternate spellings (e.g. "x86_64" for "amd64") release
+// maintainers use, without discarding the template's own aliasing.
+func AssetContexts(tmplCtx templates.Context) []templates.Context {
+	var contexts []templates.Context
+	seen := make(map[string]bool)
+
+	add := func(arch string) {
+		if seen[arch] {
+			return
+		}
+		seen[arch] = true
+
+		ctx := tmplCtx
+		ctx.Arch = arch
+		ctx.OSArch = ctx.OS + "-" + arch
+		contexts = append(contexts, ctx)
+	}
+
+	add(tmplCtx.Arch)
+	for _, arch := range ArchAliases(tmplCtx.Arch) {
+		add(arch)
+	}
+
+	return contexts
+}
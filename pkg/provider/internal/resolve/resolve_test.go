@@ -0,0 +1,143 @@
+package resolve
+
+import (
+	"testing"
+
+	"github.com/mdbooth/polymorph/pkg/templates"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		tag    string
+		want   Version
+		wantOk bool
+	}{
+		{"v1.29.3", Version{1, 29, 3}, true},
+		{"1.29.3", Version{1, 29, 3}, true},
+		{"v1.29.3-rc.1", Version{1, 29, 3}, true},
+		{"v1.29.3+build5", Version{1, 29, 3}, true},
+		{"v1.29", Version{1, 29, 0}, true},
+		{"not-a-version", Version{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseVersion(tt.tag)
+		if ok != tt.wantOk {
+			t.Errorf("ParseVersion(%q) ok = %v, want %v", tt.tag, ok, tt.wantOk)
+			continue
+		}
+		if ok && got != tt.want {
+			t.Errorf("ParseVersion(%q) = %v, want %v", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestConstraintMatches(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{">=1.29,<1.30", "v1.29.0", true},
+		{">=1.29,<1.30", "v1.29.9", true},
+		{">=1.29,<1.30", "v1.30.0", false},
+		{">=1.29,<1.30", "v1.28.9", false},
+		{"1.29.3", "v1.29.3", true},
+		{"1.29.3", "v1.29.4", false},
+	}
+
+	for _, tt := range tests {
+		constraints, err := ParseConstraints(tt.constraint)
+		if err != nil {
+			t.Fatalf("ParseConstraints(%q): %v", tt.constraint, err)
+		}
+
+		v, ok := ParseVersion(tt.version)
+		if !ok {
+			t.Fatalf("ParseVersion(%q) failed", tt.version)
+		}
+
+		if got := MatchesAll(v, constraints); got != tt.want {
+			t.Errorf("MatchesAll(%q, %q) = %v, want %v", tt.version, tt.constraint, got, tt.want)
+		}
+	}
+}
+
+func TestIsConstraint(t *testing.T) {
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{"latest", false},
+		{"v1.29.3", false},
+		{">=1.29,<1.30", true},
+		{"=1.29.3", true},
+	}
+
+	for _, tt := range tests {
+		if got := IsConstraint(tt.version); got != tt.want {
+			t.Errorf("IsConstraint(%q) = %v, want %v", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestAssetContextsTriesConfiguredArchFirst(t *testing.T) {
+	tmplCtx := templates.Context{OS: "linux", Arch: "amd64", OSArch: "linux-amd64"}
+
+	got := AssetContexts(tmplCtx)
+
+	want := []string{"amd64", "x86_64"}
+	if len(got) != len(want) {
+		t.Fatalf("AssetContexts = %v, want arches %v", got, want)
+	}
+	for i, ctx := range got {
+		if ctx.Arch != want[i] {
+			t.Errorf("AssetContexts[%d].Arch = %q, want %q", i, ctx.Arch, want[i])
+		}
+		if ctx.OS != "linux" {
+			t.Errorf("AssetContexts[%d].OS = %q, want %q", i, ctx.OS, "linux")
+		}
+		if ctx.OSArch != "linux-"+want[i] {
+			t.Errorf("AssetContexts[%d].OSArch = %q, want %q", i, ctx.OSArch, "linux-"+want[i])
+		}
+	}
+}
+
+func TestAssetContextsHonorsTemplateConfiguredAlias(t *testing.T) {
+	// A template with arch_aliases = { amd64 = "x64" } has already
+	// resolved tmplCtx.Arch to "x64" by the time AssetContexts sees it;
+	// that alias should be tried as-is rather than discarded in favor of
+	// the hardcoded amd64/x86_64 fallback.
+	tmplCtx := templates.Context{OS: "linux", Arch: "x64", OSArch: "linux-x64"}
+
+	got := AssetContexts(tmplCtx)
+
+	if len(got) != 1 || got[0].Arch != "x64" {
+		t.Errorf("AssetContexts = %v, want a single context with Arch %q", got, "x64")
+	}
+}
+
+func TestArchAliases(t *testing.T) {
+	tests := []struct {
+		arch string
+		want []string
+	}{
+		{"amd64", []string{"amd64", "x86_64"}},
+		{"arm64", []string{"arm64", "aarch64"}},
+		{"riscv64", []string{"riscv64"}},
+	}
+
+	for _, tt := range tests {
+		got := ArchAliases(tt.arch)
+		if len(got) != len(tt.want) {
+			t.Errorf("ArchAliases(%q) = %v, want %v", tt.arch, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("ArchAliases(%q) = %v, want %v", tt.arch, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,270 @@
+// Package store implements a content-addressed cache of fetched and
+// extracted artifacts, keyed by the SHA-256 digest of the fetched
+// artifact, with named refs pointing into it and flock-guarded
+// population so concurrent `polymorph exec` invocations of the same
+// template don't race.
+package store
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Store is a content-addressed cache rooted at Dir, laid out as
+//
+//	Dir/store/sha256:<digest>/   the populated artifact
+//	Dir/refs/<name>/<ref>        a symlink into store/sha256:<digest>
+//	Dir/locks/<name>.lock        an flock guarding fetch+extract for name
+//	Dir/tmp/                     staging area for in-progress fetches
+type Store struct {
+	Dir string
+}
+
+// New returns a Store rooted under the user's cache directory.
+func New() (*Store, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("error getting user cache dir: %w", err)
+	}
+	return &Store{Dir: path.Join(cacheDir, "polymorph")}, nil
+}
+
+func (s *Store) storeDir() string { return path.Join(s.Dir, "store") }
+func (s *Store) refsDir() string  { return path.Join(s.Dir, "refs") }
+func (s *Store) locksDir() string { return path.Join(s.Dir, "locks") }
+func (s *Store) tmpDir() string   { return path.Join(s.Dir, "tmp") }
+
+func (s *Store) entryDir(digest string) string {
+	return path.Join(s.storeDir(), "sha256:"+digest)
+}
+
+// TempDir returns the staging directory for name, suitable for a fetch
+// that will be adopted into the content-addressed store with Adopt.
+// Staging under the store keeps the final os.Rename on the same
+// filesystem. Unlike os.MkdirTemp, the path is stable across calls with
+// the same name and its contents are left alone if it already exists, so
+// a download left behind by a cancelled or failed fetch is still there
+// for httpfetch.Fetch to resume next time. Callers are serialized per
+// name by Lock, so this doesn't race.
+func (s *Store) TempDir(name string) (string, error) {
+	dir := path.Join(s.tmpDir(), name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("error creating temp dir %s: %w", dir, err)
+	}
+	return dir, nil
+}
+
+// Lock takes an exclusive OS-level file lock for name, blocking until it
+// is available. Callers should hold it across fetch+extract+Adopt.
+func (s *Store) Lock(name string) (*Lock, error) {
+	if err := os.MkdirAll(s.locksDir(), 0755); err != nil {
+		return nil, fmt.Errorf("error creating locks dir %s: %w", s.locksDir(), err)
+	}
+
+	lockPath := path.Join(s.locksDir(), name+".lock")
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening lock file %s: %w", lockPath, err)
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("error locking %s: %w", lockPath, err)
+	}
+
+	return &Lock{f: f}, nil
+}
+
+// Lock is a held flock, released with Unlock.
+type Lock struct {
+	f *os.File
+}
+
+// Unlock releases the lock.
+func (l *Lock) Unlock() error {
+	defer l.f.Close()
+	return syscall.Flock(int(l.f.Fd()), syscall.LOCK_UN)
+}
+
+// RefPath returns the symlink path for name/ref.
+func (s *Store) RefPath(name, ref string) string {
+	return path.Join(s.refsDir(), name, ref)
+}
+
+// Resolve returns the store directory name/ref points to, if the ref
+// exists and still resolves to a populated store entry.
+func (s *Store) Resolve(name, ref string) (string, bool) {
+	target, err := filepath.EvalSymlinks(s.RefPath(name, ref))
+	if err != nil {
+		return "", false
+	}
+
+	if info, err := os.Stat(target); err != nil || !info.IsDir() {
+		return "", false
+	}
+
+	return target, true
+}
+
+// Adopt moves tempDir into the content-addressed store under digest,
+// deduplicating against an existing entry with the same digest, then
+// points name/ref at it. It returns the final store directory.
+func (s *Store) Adopt(digest, name, ref, tempDir string) (string, error) {
+	entryDir := s.entryDir(digest)
+
+	if _, err := os.Stat(entryDir); err == nil {
+		// Another process already populated this content.
+		os.RemoveAll(tempDir)
+	} else {
+		if err := os.MkdirAll(s.storeDir(), 0755); err != nil {
+			return "", fmt.Errorf("error creating store dir %s: %w", s.storeDir(), err)
+		}
+		if err := os.Rename(tempDir, entryDir); err != nil {
+			// A different template name can race us to adopt the same
+			// digest concurrently: if entryDir is now populated, treat
+			// that as the winner and reuse it rather than erroring.
+			if _, statErr := os.Stat(entryDir); statErr == nil {
+				os.RemoveAll(tempDir)
+			} else {
+				return "", fmt.Errorf("error adopting %s into store: %w", tempDir, err)
+			}
+		}
+	}
+
+	if err := s.setRef(name, ref, entryDir); err != nil {
+		return "", err
+	}
+
+	return entryDir, nil
+}
+
+// setRef atomically points refs/name/ref at entryDir.
+func (s *Store) setRef(name, ref, entryDir string) error {
+	refDir := path.Join(s.refsDir(), name)
+	if err := os.MkdirAll(refDir, 0755); err != nil {
+		return fmt.Errorf("error creating refs dir %s: %w", refDir, err)
+	}
+
+	refPath := path.Join(refDir, ref)
+	tempLink := refPath + ".tmp"
+	os.Remove(tempLink)
+
+	if err := os.Symlink(entryDir, tempLink); err != nil {
+		return fmt.Errorf("error creating ref symlink %s: %w", tempLink, err)
+	}
+
+	if err := os.Rename(tempLink, refPath); err != nil {
+		return fmt.Errorf("error installing ref symlink %s: %w", refPath, err)
+	}
+
+	return nil
+}
+
+// Entry describes a single content-addressed store entry.
+type Entry struct {
+	Digest   string
+	Path     string
+	ModTime  time.Time
+	RefCount int
+}
+
+// List returns every entry in the store, with how many refs point at
+// each.
+func (s *Store) List() ([]Entry, error) {
+	refCounts, err := s.refCounts()
+	if err != nil {
+		return nil, err
+	}
+
+	dirEntries, err := os.ReadDir(s.storeDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading store dir %s: %w", s.storeDir(), err)
+	}
+
+	var entries []Entry
+	for _, de := range dirEntries {
+		info, err := de.Info()
+		if err != nil {
+			return nil, fmt.Errorf("error statting %s: %w", de.Name(), err)
+		}
+
+		entries = append(entries, Entry{
+			Digest:   strings.TrimPrefix(de.Name(), "sha256:"),
+			Path:     path.Join(s.storeDir(), de.Name()),
+			ModTime:  info.ModTime(),
+			RefCount: refCounts[de.Name()],
+		})
+	}
+
+	return entries, nil
+}
+
+func (s *Store) refCounts() (map[string]int, error) {
+	counts := map[string]int{}
+
+	err := filepath.WalkDir(s.refsDir(), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+
+		target, err := filepath.EvalSymlinks(p)
+		if err != nil {
+			// Dangling ref; ignore.
+			return nil
+		}
+
+		counts[path.Base(target)]++
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error walking refs dir %s: %w", s.refsDir(), err)
+	}
+
+	return counts, nil
+}
+
+// GC removes unreferenced store entries whose last modification is older
+// than olderThan, returning the digests it removed.
+func (s *Store) GC(olderThan time.Duration) ([]string, error) {
+	entries, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+
+	var removed []string
+	for _, e := range entries {
+		if e.RefCount > 0 || e.ModTime.After(cutoff) {
+			continue
+		}
+
+		if err := os.RemoveAll(e.Path); err != nil {
+			return removed, fmt.Errorf("error removing %s: %w", e.Path, err)
+		}
+		removed = append(removed, e.Digest)
+	}
+
+	return removed, nil
+}
+
+// Remove deletes a single store entry by digest, regardless of whether
+// it is still referenced.
+func (s *Store) Remove(digest string) error {
+	return os.RemoveAll(s.entryDir(digest))
+}
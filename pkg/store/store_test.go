@@ -0,0 +1,148 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) *Store {
+	return &Store{Dir: t.TempDir()}
+}
+
+func populate(t *testing.T, s *Store, name string, content string) string {
+	tempDir, err := s.TempDir(name)
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	if err := os.WriteFile(path.Join(tempDir, "file"), []byte(content), 0644); err != nil {
+		t.Fatalf("error writing to tempDir: %v", err)
+	}
+	return tempDir
+}
+
+func TestAdoptDeduplicatesSameDigest(t *testing.T) {
+	s := newTestStore(t)
+
+	tempDir1 := populate(t, s, "template-a", "same content")
+	entryDir1, err := s.Adopt("digest", "template-a", "v1", tempDir1)
+	if err != nil {
+		t.Fatalf("Adopt: %v", err)
+	}
+
+	tempDir2 := populate(t, s, "template-b", "same content")
+	entryDir2, err := s.Adopt("digest", "template-b", "v1", tempDir2)
+	if err != nil {
+		t.Fatalf("Adopt: %v", err)
+	}
+
+	if entryDir1 != entryDir2 {
+		t.Errorf("Adopt of the same digest from two templates returned different entries: %q, %q", entryDir1, entryDir2)
+	}
+	if _, err := os.Stat(tempDir2); !os.IsNotExist(err) {
+		t.Errorf("tempDir2 %s should have been removed once deduplicated", tempDir2)
+	}
+}
+
+// TestAdoptConcurrentSameDigest exercises two different templates that
+// happen to fetch byte-identical content racing to adopt the same
+// digest: Store.Lock only serializes same-name fetches, so this is the
+// one scenario where two Adopt calls can genuinely race.
+func TestAdoptConcurrentSameDigest(t *testing.T) {
+	s := newTestStore(t)
+
+	const n = 8
+	entryDirs := make([]string, n)
+	errs := make([]error, n)
+	tempDirs := make([]string, n)
+	for i := 0; i < n; i++ {
+		tempDirs[i] = populate(t, s, fmt.Sprintf("template-%d", i), "race content")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			entryDirs[i], errs[i] = s.Adopt("racedigest", fmt.Sprintf("template-%d", i), "v1", tempDirs[i])
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("Adopt[%d]: %v", i, err)
+		}
+	}
+	for i := 1; i < n; i++ {
+		if entryDirs[i] != entryDirs[0] {
+			t.Errorf("Adopt[%d] returned %q, want %q", i, entryDirs[i], entryDirs[0])
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	s := newTestStore(t)
+
+	tempDir := populate(t, s, "template", "content")
+	entryDir, err := s.Adopt("digest", "template", "v1", tempDir)
+	if err != nil {
+		t.Fatalf("Adopt: %v", err)
+	}
+
+	got, ok := s.Resolve("template", "v1")
+	if !ok {
+		t.Fatal("Resolve returned ok=false for a freshly adopted ref")
+	}
+	if got != entryDir {
+		t.Errorf("Resolve = %q, want %q", got, entryDir)
+	}
+
+	if _, ok := s.Resolve("template", "unknown"); ok {
+		t.Error("Resolve of an unknown ref returned ok=true")
+	}
+}
+
+func TestGC(t *testing.T) {
+	s := newTestStore(t)
+
+	oldTempDir := populate(t, s, "old", "stale")
+	oldEntryDir, err := s.Adopt("old-digest", "old", "v1", oldTempDir)
+	if err != nil {
+		t.Fatalf("Adopt: %v", err)
+	}
+	// Simulate the entry becoming unreferenced (e.g. the template no
+	// longer resolves to this ref): GC only removes entries with no ref
+	// pointing at them.
+	if err := os.Remove(s.RefPath("old", "v1")); err != nil {
+		t.Fatalf("error removing ref: %v", err)
+	}
+	oldTime := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(oldEntryDir, oldTime, oldTime); err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+
+	keptTempDir := populate(t, s, "kept", "fresh")
+	if _, err := s.Adopt("kept-digest", "kept", "v1", keptTempDir); err != nil {
+		t.Fatalf("Adopt: %v", err)
+	}
+
+	removed, err := s.GC(24 * time.Hour)
+	if err != nil {
+		t.Fatalf("GC: %v", err)
+	}
+
+	if len(removed) != 1 || removed[0] != "old-digest" {
+		t.Errorf("GC removed %v, want [\"old-digest\"]", removed)
+	}
+	if _, ok := s.Resolve("old", "v1"); ok {
+		t.Error("Resolve still finds the garbage-collected entry")
+	}
+	if _, ok := s.Resolve("kept", "v1"); !ok {
+		t.Error("Resolve no longer finds the entry GC should have kept")
+	}
+}
@@ -2,20 +2,130 @@ package templates
 
 import (
 	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
 	"text/template"
 )
 
-func ExpandTemplate(templateString string, params map[string]string) (string, error) {
-	t, err := template.New("").Parse(templateString)
+// Context is the data available when expanding a template, alongside the
+// helper funcs registered in funcMap.
+type Context struct {
+	// Params holds the template's user-defined parameters, referenced as
+	// {{.Params.name}}.
+	Params map[string]string
+
+	// OS and Arch are runtime.GOOS/GOARCH, each passed through the
+	// aliases given to NewContext so a template can ask for "x86_64"
+	// instead of "amd64" without a hand-written conditional.
+	OS   string
+	Arch string
+
+	// OSArch is OS and Arch joined with a "-", e.g. "linux-amd64".
+	OSArch string
+
+	// Env looks up environment variables, referenced as {{.Env.FOO}}.
+	Env map[string]string
+}
+
+// NewContext builds the Context for the current platform. osAliases and
+// archAliases remap the raw runtime.GOOS/GOARCH value (e.g. "amd64" to
+// "x86_64") and may be nil.
+func NewContext(params map[string]string, osAliases, archAliases map[string]string) Context {
+	osName := alias(osAliases, runtime.GOOS)
+	arch := alias(archAliases, runtime.GOARCH)
+
+	return Context{
+		Params: params,
+		OS:     osName,
+		Arch:   arch,
+		OSArch: osName + "-" + arch,
+		Env:    environ(),
+	}
+}
+
+func alias(aliases map[string]string, value string) string {
+	if aliased, ok := aliases[value]; ok {
+		return aliased
+	}
+	return value
+}
+
+func environ() map[string]string {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		k, v, ok := strings.Cut(kv, "=")
+		if ok {
+			env[k] = v
+		}
+	}
+	return env
+}
+
+var funcMap = template.FuncMap{
+	"upper":       strings.ToUpper,
+	"lower":       strings.ToLower,
+	"title":       title,
+	"trimPrefix":  strings.TrimPrefix,
+	"replace":     func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"semverMajor": semverMajor,
+	"semverMinor": semverMinor,
+	"default":     defaultValue,
+}
+
+func title(s string) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + strings.ToLower(w[1:])
+	}
+	return strings.Join(words, " ")
+}
+
+func semverMajor(v string) (string, error) {
+	parts, err := semverParts(v)
 	if err != nil {
 		return "", err
 	}
+	return parts[0], nil
+}
 
-	var buf bytes.Buffer
-	err = t.Execute(&buf, params)
+func semverMinor(v string) (string, error) {
+	parts, err := semverParts(v)
+	if err != nil {
+		return "", err
+	}
+	return parts[1], nil
+}
+
+func semverParts(v string) ([2]string, error) {
+	v = strings.TrimPrefix(v, "v")
+	fields := strings.SplitN(v, ".", 3)
+	if len(fields) < 2 {
+		return [2]string{}, fmt.Errorf("invalid semver %q", v)
+	}
+	return [2]string{fields[0], fields[1]}, nil
+}
+
+func defaultValue(def, val string) string {
+	if val == "" {
+		return def
+	}
+	return val
+}
+
+// ExpandTemplate parses templateString as a text/template and executes it
+// against ctx, with the helper funcs in funcMap available.
+func ExpandTemplate(templateString string, ctx Context) (string, error) {
+	t, err := template.New("").Funcs(funcMap).Parse(templateString)
 	if err != nil {
 		return "", err
 	}
 
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, ctx); err != nil {
+		return "", err
+	}
+
 	return buf.String(), nil
 }